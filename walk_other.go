@@ -0,0 +1,72 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package powerwalk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrSkipFiles, returned from the walkFn passed to WalkFast, skips the
+// remaining siblings in the current directory.
+var ErrSkipFiles = errors.New("powerwalk: skip remaining files in directory")
+
+// ErrTraverseLink, returned from the walkFn passed to WalkFast for a
+// symlink, causes WalkFast to recurse into it as though it were a
+// directory.
+var ErrTraverseLink = errors.New("powerwalk: traverse symlinked directory")
+
+// WalkFast walks the file tree rooted at root like WalkFast on unix-like
+// platforms, honoring ErrSkipFiles, but this build has no OS-level d_type
+// to read directory entries from cheaply, so it falls back to the
+// os.Lstat-based engine behind WalkLimit and simply narrows each
+// os.FileInfo down to its type bits before calling walkFn.
+//
+// ErrSkipFiles is only best-effort on this fallback, unlike the strict,
+// deterministic guarantee the unix backend provides. WalkLimit dispatches
+// a directory's siblings to its worker pool fully concurrently rather than
+// one at a time, so by the time ErrSkipFiles marks a directory as skipped,
+// walkFn may already have been called for some of its later siblings.
+//
+// ErrTraverseLink cannot be honored on this fallback: the underlying
+// filepath.Walk never follows symbolic links, with no way for a caller to
+// override that per-entry, so returning it from walkFn is accepted but has
+// no effect.
+//
+// As on the unix backend, any other non-nil error from walkFn cancels the
+// walk; here it is returned wrapped in a WalkErrors.
+func WalkFast(root string, walkFn func(path string, typ os.FileMode) error, limit int) error {
+	var (
+		mu          sync.Mutex
+		skippedDirs = map[string]bool{}
+	)
+
+	return WalkLimit(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		dir := filepath.Dir(path)
+		mu.Lock()
+		skip := skippedDirs[dir]
+		mu.Unlock()
+		if skip {
+			return nil
+		}
+
+		switch ferr := walkFn(path, info.Mode()&os.ModeType); ferr {
+		case nil, ErrTraverseLink:
+			return nil
+		case ErrSkipFiles:
+			mu.Lock()
+			skippedDirs[dir] = true
+			mu.Unlock()
+			return nil
+		default:
+			return ferr
+		}
+	}, limit, WalkOptions{StopOnFirstError: true})
+}
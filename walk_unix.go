@@ -0,0 +1,218 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package powerwalk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrSkipFiles, returned from the walkFn passed to WalkFast, skips the
+// remaining siblings in the current directory: walkFn is not called for
+// them. Unlike filepath.SkipDir, those remaining siblings that are
+// subdirectories are still recursed into.
+var ErrSkipFiles = errors.New("powerwalk: skip remaining files in directory")
+
+// ErrTraverseLink, returned from the walkFn passed to WalkFast for a
+// symlink, causes WalkFast to recurse into it as though it were a
+// directory. WalkFast does not otherwise follow symbolic links.
+var ErrTraverseLink = errors.New("powerwalk: traverse symlinked directory")
+
+// WalkFast walks the file tree rooted at root, calling walkFn for each file
+// or directory in the tree, including root. Unlike Walk and WalkLimit,
+// WalkFast reads each directory's entries using the type bits the operating
+// system's directory-reading syscalls already return, so walkFn is handed
+// an os.FileMode carrying only the entry's type -- no os.Lstat is performed
+// per entry. Callers that need the full os.FileInfo must stat the path
+// themselves.
+//
+// Returning ErrSkipFiles from walkFn skips the remaining siblings in the
+// current directory without skipping their subdirectories. Returning
+// ErrTraverseLink for a symlink makes WalkFast recurse into it. Any other
+// non-nil error cancels the walk and is returned by WalkFast.
+//
+// A maximum of limit walkFns will be called at any one time.
+func WalkFast(root string, walkFn func(path string, typ os.FileMode) error, limit int) error {
+
+	// make sure limit is sensible
+	if limit < 1 {
+		panic("powerwalk: limit must be greater than zero.")
+	}
+
+	queue := newPathQueue()
+	kill := make(chan struct{})
+	errs := make(chan error, 1)
+
+	var pending sync.WaitGroup
+
+	fail := func(err error) {
+		select {
+		case errs <- err:
+			close(kill)
+		default:
+		}
+	}
+
+	// enqueue must never block on the worker pool it feeds: a worker calls
+	// enqueue from inside walkFastDir while holding its only slot in the
+	// pool, so a channel sized to the pool can fill up with no one left to
+	// drain it. queue is an unbounded, mutex-backed FIFO instead.
+	enqueue := func(path string) {
+		pending.Add(1)
+		queue.push(path)
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				dir, ok := queue.pop()
+				if !ok {
+					return
+				}
+				walkFastDir(dir, walkFn, enqueue, fail, kill)
+				pending.Done()
+			}
+		}()
+	}
+
+	rootInfo, err := os.Lstat(root)
+	if err != nil {
+		return err
+	}
+	if ferr := walkFn(root, rootInfo.Mode()&os.ModeType); ferr != nil {
+		return ferr
+	}
+	if !rootInfo.IsDir() {
+		return nil
+	}
+
+	enqueue(root)
+
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-kill:
+	}
+
+	// wake any worker blocked waiting for more work, whether we got here
+	// because the walk finished or because it was cancelled early.
+	queue.close()
+	workers.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// walkFastDir reads a single directory's entries and dispatches walkFn for
+// each of them, enqueuing subdirectories (and, via ErrTraverseLink,
+// symlinks) as new work units.
+func walkFastDir(dir string, walkFn func(path string, typ os.FileMode) error, enqueue func(string), fail func(error), kill chan struct{}) {
+	f, err := os.Open(dir)
+	if err != nil {
+		fail(err)
+		return
+	}
+	entries, err := f.ReadDir(-1)
+	f.Close()
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	skipRest := false
+	for _, ent := range entries {
+		select {
+		case <-kill:
+			return
+		default:
+		}
+
+		path := filepath.Join(dir, ent.Name())
+		typ := ent.Type()
+
+		if skipRest {
+			if typ.IsDir() {
+				enqueue(path)
+			}
+			continue
+		}
+
+		switch err := walkFn(path, typ); err {
+		case nil:
+		case ErrSkipFiles:
+			skipRest = true
+		case ErrTraverseLink:
+			if typ&os.ModeSymlink != 0 {
+				enqueue(path)
+			}
+			continue
+		default:
+			fail(err)
+			return
+		}
+
+		if typ.IsDir() {
+			enqueue(path)
+		}
+	}
+}
+
+// pathQueue is an unbounded FIFO queue of directory paths still awaiting a
+// walkFastDir pass. Unlike a buffered channel, pushing to it never blocks,
+// so it can safely be fed by the same worker pool that drains it.
+type pathQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []string
+	closed bool
+}
+
+func newPathQueue() *pathQueue {
+	q := &pathQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *pathQueue) push(path string) {
+	q.mu.Lock()
+	q.items = append(q.items, path)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// pop blocks until a path is available or the queue is closed, in which
+// case ok is false.
+func (q *pathQueue) pop() (path string, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return "", false
+	}
+	path, q.items = q.items[0], q.items[1:]
+	return path, true
+}
+
+func (q *pathQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
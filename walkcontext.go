@@ -0,0 +1,124 @@
+package powerwalk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WalkContext walks the file tree rooted at root like WalkLimit with
+// StopOnFirstError set, calling walkFn for each file or directory in the
+// tree, including root, and honoring filepath.SkipDir. The first error
+// returned by walkFn cancels the walk and is returned as is, not wrapped in
+// a WalkErrors. In addition, WalkContext stops the walk and returns
+// ctx.Err() as soon as ctx is done.
+//
+// WalkContext funnels cancellation, the first walkFn error, and normal
+// completion through a single context derived from ctx, so there is exactly
+// one place that decides the walk is over.
+//
+// A maximum of limit walkFns will be called at any one time.
+func WalkContext(parent context.Context, root string, walkFn filepath.WalkFunc, limit int) error {
+
+	// make sure limit is sensible
+	if limit < 1 {
+		panic("powerwalk: limit must be greater than zero.")
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	files := make(chan *walkArgs)
+
+	var (
+		once    sync.Once
+		walkErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			walkErr = err
+		})
+		cancel()
+	}
+
+	var workers sync.WaitGroup
+	workers.Add(limit)
+	for i := 0; i < limit; i++ {
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case file := <-files:
+					err := walkFn(file.path, file.info, file.err)
+					if file.resp != nil {
+						select {
+						case file.resp <- err:
+						case <-ctx.Done():
+						}
+						continue
+					}
+					if err != nil {
+						fail(err)
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	var producer sync.WaitGroup
+	producer.Add(1)
+	go func() {
+		defer producer.Done()
+
+		filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			select {
+			case <-ctx.Done():
+				return errors.New("Error in walk. Cannot continue.")
+			default:
+			}
+
+			if info != nil && info.IsDir() {
+				resp := make(chan error, 1)
+				select {
+				case files <- &walkArgs{path: p, info: info, err: err, resp: resp}:
+				case <-ctx.Done():
+					return errors.New("Error in walk. Cannot continue.")
+				}
+
+				select {
+				case werr := <-resp:
+					if werr == filepath.SkipDir {
+						return filepath.SkipDir
+					}
+					if werr != nil {
+						fail(werr)
+						return errors.New("Error in walk. Cannot continue.")
+					}
+					return nil
+				case <-ctx.Done():
+					return errors.New("Error in walk. Cannot continue.")
+				}
+			}
+
+			select {
+			case files <- &walkArgs{path: p, info: info, err: err}:
+			case <-ctx.Done():
+				return errors.New("Error in walk. Cannot continue.")
+			}
+			return nil
+		})
+	}()
+
+	producer.Wait()
+	cancel()
+	workers.Wait()
+
+	if walkErr != nil {
+		return walkErr
+	}
+	return parent.Err()
+}
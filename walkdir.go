@@ -0,0 +1,161 @@
+package powerwalk
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sync"
+)
+
+// WalkDir walks the file tree rooted at root, calling walkFn for each file or
+// directory in the tree, including root. All errors that arise visiting files
+// and directories are filtered by walkFn. The output is non-deterministic.
+// WalkDir does not follow symbolic links.
+//
+// Unlike Walk, WalkDir is backed by filepath.WalkDir and so does not call
+// os.Lstat on every entry; walkFn is handed an fs.DirEntry instead of an
+// os.FileInfo, and only pays for the extra stat when it actually calls
+// DirEntry.Info(). On large trees this saves a significant number of
+// syscalls.
+//
+// For each file and directory encountered, WalkDir will trigger a new Go
+// routine allowing you to handle each item concurrently. A maximum of
+// DefaultConcurrentWalks walkFns will be called at any one time.
+func WalkDir(root string, walkFn fs.WalkDirFunc) error {
+	return WalkDirLimit(root, walkFn, DefaultConcurrentWalks)
+}
+
+// WalkDirLimit walks the file tree rooted at root, calling walkFn for each
+// file or directory in the tree, including root. All errors that arise
+// visiting files and directories are filtered by walkFn. The output is
+// non-deterministic. WalkDirLimit does not follow symbolic links.
+//
+// Every file and directory visited by the underlying filepath.WalkDir is
+// guaranteed to be delivered to a walkFn call; WalkDirLimit does not drop
+// entries when all workers are busy. If walkFn returns fs.SkipDir for a
+// directory, that directory's subtree is skipped, exactly as with
+// filepath.WalkDir itself. The first non-nil error returned by walkFn
+// (other than fs.SkipDir for a directory) cancels the walk and is returned
+// by WalkDirLimit; fs.SkipDir returned for a file is treated like any other
+// error.
+//
+// For each file and directory encountered, WalkDirLimit will trigger a new Go
+// routine allowing you to handle each item concurrently. A maximum of limit
+// walkFns will be called at any one time.
+func WalkDirLimit(root string, walkFn fs.WalkDirFunc, limit int) error {
+
+	// make sure limit is sensible
+	if limit < 1 {
+		panic("powerwalk: limit must be greater than zero.")
+	}
+
+	files := make(chan *walkDirArgs)
+	kill := make(chan struct{})
+
+	var (
+		mu      sync.Mutex
+		walkErr error
+	)
+
+	// closeKill is the single place that closes kill. sync.Once makes it
+	// safe to call from multiple goroutines -- a failing walkFn and the
+	// normal-completion path below -- without a gap between "should I
+	// close" and "close" for another caller to race through.
+	var closeKill sync.Once
+	fail := func(err error) {
+		mu.Lock()
+		if walkErr == nil {
+			walkErr = err
+		}
+		mu.Unlock()
+		closeKill.Do(func() { close(kill) })
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		workers.Add(1)
+		go func(i int) {
+			defer workers.Done()
+			for {
+				select {
+				case file := <-files:
+					err := walkFn(file.path, file.d, file.err)
+					if file.resp != nil {
+						// the producer is waiting to learn whether it should
+						// prune this directory's subtree.
+						select {
+						case file.resp <- err:
+						case <-kill:
+						}
+						continue
+					}
+					if err != nil {
+						fail(err)
+					}
+				case <-kill:
+					return
+				}
+			}
+		}(i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			select {
+			case <-kill:
+				return errors.New("Error in walk. Cannot continue.")
+			default:
+			}
+
+			// directories get a response channel so a walkFn-returned
+			// fs.SkipDir can make its way back to filepath.WalkDir.
+			if d != nil && d.IsDir() {
+				resp := make(chan error, 1)
+				select {
+				case files <- &walkDirArgs{path: p, d: d, err: err, resp: resp}:
+				case <-kill:
+					return errors.New("Error in walk. Cannot continue.")
+				}
+
+				select {
+				case werr := <-resp:
+					if werr == fs.SkipDir {
+						return fs.SkipDir
+					}
+					if werr != nil {
+						fail(werr)
+					}
+					return nil
+				case <-kill:
+					return errors.New("Error in walk. Cannot continue.")
+				}
+			}
+
+			select {
+			case files <- &walkDirArgs{path: p, d: d, err: err}:
+			case <-kill:
+				return errors.New("Error in walk. Cannot continue.")
+			}
+			return nil
+		})
+
+		wg.Done()
+	}()
+
+	wg.Wait()
+	closeKill.Do(func() { close(kill) })
+
+	workers.Wait()
+
+	return walkErr
+}
+
+type walkDirArgs struct {
+	path string
+	d    fs.DirEntry
+	err  error
+	resp chan error // non-nil for directories; carries walkFn's return value back to the filepath.WalkDir callback
+}
@@ -0,0 +1,37 @@
+package powerwalk
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWalkDirLimitRaceOnLastFileError guards against a regression where an
+// error returned by walkFn for the last-dispatched file raced the normal
+// end-of-walk completion path, closing an already-closed kill channel.
+func TestWalkDirLimitRaceOnLastFileError(t *testing.T) {
+	root := t.TempDir()
+	var last string
+	for i := 0; i < 20; i++ {
+		p := filepath.Join(root, string(rune('a'+i)))
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		last = p
+	}
+
+	wantErr := errors.New("boom")
+	for i := 0; i < 50; i++ {
+		err := WalkDirLimit(root, func(p string, d fs.DirEntry, err error) error {
+			if p == last {
+				return wantErr
+			}
+			return nil
+		}, 8)
+		if err != wantErr {
+			t.Fatalf("run %d: expected %v, got %v", i, wantErr, err)
+		}
+	}
+}
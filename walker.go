@@ -2,8 +2,10 @@ package powerwalk
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -11,6 +13,30 @@ import (
 // same time.
 const DefaultConcurrentWalks int = 50
 
+// WalkOptions configures the behavior of WalkLimit.
+type WalkOptions struct {
+	// StopOnFirstError, if true, cancels the walk as soon as walkFn returns
+	// its first error, matching WalkLimit's historical behavior. The
+	// default, false, lets the walk run to completion and returns every
+	// error collected along the way as a WalkErrors.
+	StopOnFirstError bool
+}
+
+// WalkErrors collects every error returned by walkFn during a walk. It
+// implements error so it can be returned directly from WalkLimit.
+type WalkErrors []error
+
+func (e WalkErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("powerwalk: %d errors occurred: %s", len(e), strings.Join(msgs, "; "))
+}
+
 // Walk walks the file tree rooted at root, calling walkFn for each file or
 // directory in the tree, including root. All errors that arise visiting files
 // and directories are filtered by walkFn. The output is non-deterministic.
@@ -28,27 +54,75 @@ func Walk(root string, walkFn filepath.WalkFunc) error {
 // and directories are filtered by walkFn. The output is non-deterministic.
 // WalkLimit does not follow symbolic links.
 //
+// Every file and directory visited by the underlying filepath.Walk is
+// guaranteed to be delivered to a walkFn call; WalkLimit no longer drops
+// entries when all workers are busy. If walkFn returns filepath.SkipDir for
+// a directory, that directory's subtree is skipped, exactly as with
+// filepath.Walk itself.
+//
+// By default WalkLimit runs the walk to completion and returns every error
+// returned by walkFn as a WalkErrors. Passing a WalkOptions with
+// StopOnFirstError set cancels the walk as soon as the first error comes
+// back, which was WalkLimit's original, fail-fast behavior.
+//
 // For each file and directory encountered, Walk will trigger a new Go routine
 // allowing you to handle each item concurrently.  A maximum of limit walkFns will
 // be called at any one time.
-func WalkLimit(root string, walkFn filepath.WalkFunc, limit int) error {
+func WalkLimit(root string, walkFn filepath.WalkFunc, limit int, opts ...WalkOptions) error {
 
 	// make sure limit is sensible
 	if limit < 1 {
 		panic("powerwalk: limit must be greater than zero.")
 	}
 
+	var opt WalkOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
 	files := make(chan *walkArgs)
 	kill := make(chan struct{})
-	errs := make(chan error)
 
+	var (
+		mu   sync.Mutex
+		errs WalkErrors
+	)
+
+	// closeKill is the single place that closes kill. sync.Once makes it
+	// safe to call from multiple goroutines -- a failing walkFn under
+	// StopOnFirstError, and the normal-completion path below -- without a
+	// gap between "should I close" and "close" for another caller to race
+	// through.
+	var closeKill sync.Once
+	recordErr := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+		if opt.StopOnFirstError {
+			closeKill.Do(func() { close(kill) })
+		}
+	}
+
+	var workers sync.WaitGroup
 	for i := 0; i < limit; i++ {
+		workers.Add(1)
 		go func(i int) {
+			defer workers.Done()
 			for {
 				select {
 				case file := <-files:
-					if err := walkFn(file.path, file.info, file.err); err != nil {
-						errs <- err
+					err := walkFn(file.path, file.info, file.err)
+					if file.resp != nil {
+						// the producer is waiting to learn whether it should
+						// prune this directory's subtree.
+						select {
+						case file.resp <- err:
+						case <-kill:
+						}
+						continue
+					}
+					if err != nil {
+						recordErr(err)
 					}
 				case <-kill:
 					return
@@ -57,18 +131,6 @@ func WalkLimit(root string, walkFn filepath.WalkFunc, limit int) error {
 		}(i)
 	}
 
-	var walkErr error
-
-	// check for errors
-	go func() {
-		select {
-		case walkErr = <-errs:
-			close(kill)
-		case <-kill:
-			return
-		}
-	}()
-
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -76,31 +138,62 @@ func WalkLimit(root string, walkFn filepath.WalkFunc, limit int) error {
 		filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
 			select {
 			case <-kill:
-				close(files)
 				return errors.New("Error in walk. Cannot continue.")
 			default:
+			}
+
+			// directories get a response channel so a walkFn-returned
+			// filepath.SkipDir can make its way back to filepath.Walk.
+			if info != nil && info.IsDir() {
+				resp := make(chan error, 1)
 				select {
-				case files <- &walkArgs{path: p, info: info, err: err}:
-				default:
+				case files <- &walkArgs{path: p, info: info, err: err, resp: resp}:
+				case <-kill:
+					return errors.New("Error in walk. Cannot continue.")
+				}
+
+				select {
+				case werr := <-resp:
+					if werr == filepath.SkipDir {
+						return filepath.SkipDir
+					}
+					if werr != nil {
+						recordErr(werr)
+					}
+					return nil
+				case <-kill:
+					return errors.New("Error in walk. Cannot continue.")
 				}
-				return nil
 			}
+
+			select {
+			case files <- &walkArgs{path: p, info: info, err: err}:
+			case <-kill:
+				return errors.New("Error in walk. Cannot continue.")
+			}
+			return nil
 		})
 
 		wg.Done()
 	}()
 
 	wg.Wait()
+	closeKill.Do(func() { close(kill) })
 
-	if walkErr == nil {
-		close(kill)
-	}
+	// wait for every worker to finish processing (and recording the error
+	// from) whatever it was handed before the walk ended, so a walkFn call
+	// still in flight when the producer finishes can't be lost from errs.
+	workers.Wait()
 
-	return walkErr
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
 }
 
 type walkArgs struct {
 	path string
 	info os.FileInfo
 	err  error
+	resp chan error // non-nil for directories; carries walkFn's return value back to the filepath.Walk callback
 }
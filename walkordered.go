@@ -0,0 +1,203 @@
+package powerwalk
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WalkOrdered walks the file tree rooted at root, calling walkFn for each
+// file or directory in the tree, including root. Unlike Walk and WalkLimit,
+// WalkOrdered is deterministic: walkFn is always called for a directory
+// before it is called for any of that directory's descendants, and siblings
+// within a directory are visited in lexical order. Independent subtrees are
+// still walked concurrently -- a maximum of limit directories will have
+// their entries read, and passed to walkFn, at any one time.
+func WalkOrdered(root string, walkFn filepath.WalkFunc, limit int) error {
+
+	// make sure limit is sensible
+	if limit < 1 {
+		panic("powerwalk: limit must be greater than zero.")
+	}
+
+	queue := newDirTaskQueue()
+	kill := make(chan struct{})
+	errs := make(chan error, 1)
+
+	var pending sync.WaitGroup
+
+	fail := func(err error) {
+		select {
+		case errs <- err:
+			close(kill)
+		default:
+		}
+	}
+
+	// enqueue must never block on the worker pool it feeds: a worker calls
+	// enqueue from inside walkOrderedDirEntries while holding its only slot
+	// in the pool, so a channel sized to the pool can fill up with no one
+	// left to drain it. queue is an unbounded, mutex-backed FIFO instead.
+	enqueue := func(path string, info os.FileInfo) {
+		pending.Add(1)
+		queue.push(dirTask{path, info})
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				d, ok := queue.pop()
+				if !ok {
+					return
+				}
+				walkOrderedDirEntries(d.path, d.info, walkFn, enqueue, fail)
+				pending.Done()
+			}
+		}()
+	}
+
+	rootInfo, err := os.Lstat(root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+
+	recurse, ferr := visit(root, rootInfo, walkFn)
+	if ferr != nil {
+		fail(ferr)
+	} else if recurse {
+		enqueue(root, rootInfo)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-kill:
+	}
+
+	// wake any worker blocked waiting for more work, whether we got here
+	// because the walk finished or because it was cancelled early.
+	queue.close()
+	workers.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// visit calls walkFn for path and reports whether its subtree (if any)
+// should still be read. It is always called synchronously, in lexical
+// order, from the single directory that produced path -- never deferred to
+// the worker pool -- so that a sibling's walkFn call can never be observed
+// out of order relative to the directory entries around it.
+func visit(path string, info os.FileInfo, walkFn filepath.WalkFunc) (recurse bool, err error) {
+	ferr := walkFn(path, info, nil)
+	if ferr != nil {
+		if ferr == filepath.SkipDir {
+			return false, nil
+		}
+		return false, ferr
+	}
+	return info != nil && info.IsDir(), nil
+}
+
+// walkOrderedDirEntries reads dir's entries and, in lexical order, calls
+// visit for each of them so their walkFn calls land strictly before those
+// of later siblings. Only the recursive expansion of a subdirectory's own
+// entries -- not its walkFn call, which visit already made -- is deferred
+// as a new work unit so it can be read concurrently with dir's siblings.
+func walkOrderedDirEntries(dir string, info os.FileInfo, walkFn filepath.WalkFunc, enqueue func(string, os.FileInfo), fail func(error)) {
+	// os.ReadDir already returns entries sorted by filename.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if ferr := walkFn(dir, info, err); ferr != nil && ferr != filepath.SkipDir {
+			fail(ferr)
+		}
+		return
+	}
+
+	for _, ent := range entries {
+		path := filepath.Join(dir, ent.Name())
+		entInfo, err := ent.Info()
+
+		if err != nil {
+			if ferr := walkFn(path, entInfo, err); ferr != nil && ferr != filepath.SkipDir {
+				fail(ferr)
+				return
+			}
+			continue
+		}
+
+		recurse, ferr := visit(path, entInfo, walkFn)
+		if ferr != nil {
+			fail(ferr)
+			return
+		}
+		if recurse {
+			enqueue(path, entInfo)
+		}
+	}
+}
+
+// dirTask is one unit of work for WalkOrdered: a directory that visit has
+// already passed to walkFn and whose entries are still to be read, along
+// with its already-Lstat'd info.
+type dirTask struct {
+	path string
+	info os.FileInfo
+}
+
+// dirTaskQueue is an unbounded FIFO queue of dirTasks. Unlike a buffered
+// channel, pushing to it never blocks, so it can safely be fed by the same
+// worker pool that drains it.
+type dirTaskQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []dirTask
+	closed bool
+}
+
+func newDirTaskQueue() *dirTaskQueue {
+	q := &dirTaskQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *dirTaskQueue) push(item dirTask) {
+	q.mu.Lock()
+	q.items = append(q.items, item)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// pop blocks until an item is available or the queue is closed, in which
+// case ok is false.
+func (q *dirTaskQueue) pop() (item dirTask, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return dirTask{}, false
+	}
+	item, q.items = q.items[0], q.items[1:]
+	return item, true
+}
+
+func (q *dirTaskQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
@@ -0,0 +1,67 @@
+package powerwalk
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestWalkOrderedSiblingOrder guards against a regression where a
+// subdirectory in the middle of a listing deferred its own walkFn call to
+// the worker pool instead of making it synchronously, letting later
+// siblings' walkFn calls race ahead of it.
+func TestWalkOrderedSiblingOrder(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "a_file.txt"))
+	if err := os.Mkdir(filepath.Join(root, "b_subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mustWriteFile(t, filepath.Join(root, "b_subdir", "inner.txt"))
+	mustWriteFile(t, filepath.Join(root, "c_file.txt"))
+	mustWriteFile(t, filepath.Join(root, "d_file.txt"))
+
+	want := []string{
+		filepath.Join(root, "a_file.txt"),
+		filepath.Join(root, "b_subdir"),
+		filepath.Join(root, "c_file.txt"),
+		filepath.Join(root, "d_file.txt"),
+	}
+
+	for i := 0; i < 200; i++ {
+		var (
+			mu   sync.Mutex
+			seen []string
+		)
+		err := WalkOrdered(root, func(path string, info os.FileInfo, err error) error {
+			if path == root {
+				return nil
+			}
+			if path == filepath.Join(root, "b_subdir", "inner.txt") {
+				return nil
+			}
+			mu.Lock()
+			seen = append(seen, path)
+			mu.Unlock()
+			return nil
+		}, 8)
+		if err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		if len(seen) != len(want) {
+			t.Fatalf("run %d: got %v, want %v", i, seen, want)
+		}
+		for j, p := range want {
+			if seen[j] != p {
+				t.Fatalf("run %d: got %v, want %v", i, seen, want)
+			}
+		}
+	}
+}
+
+func mustWriteFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+}